@@ -0,0 +1,307 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kiro2api/logger"
+)
+
+// APIKey 下游客户端的访问凭证，用于限制其能使用哪些上游账号、请求速率与月度额度
+type APIKey struct {
+	ID               string   `json:"id"`
+	Secret           string   `json:"secret"`
+	AllowedAccounts  []string `json:"allowedAccounts,omitempty"`  // 允许使用的上游账号ID（对应AuthConfig.ID或其cache key）；为空表示不限制
+	RateLimit        float64  `json:"rateLimit,omitempty"`        // 每秒允许的请求数，<=0表示不限流
+	MonthlyCreditCap float64  `json:"monthlyCreditCap,omitempty"` // 每自然月允许消耗的额度上限，<=0表示不限制
+}
+
+// apiKeyUsage 某个API Key在某个自然月内已消耗的额度
+type apiKeyUsage struct {
+	month string
+	used  float64
+}
+
+// APIKeyStore 管理下游API Key、其限流状态与月度用量
+type APIKeyStore struct {
+	mutex    sync.RWMutex
+	keys     map[string]*APIKey
+	limiters map[string]*rateLimiter
+	usage    map[string]apiKeyUsage
+}
+
+// NewAPIKeyStore 创建空的API Key存储
+func NewAPIKeyStore() *APIKeyStore {
+	return &APIKeyStore{
+		keys:     make(map[string]*APIKey),
+		limiters: make(map[string]*rateLimiter),
+		usage:    make(map[string]apiKeyUsage),
+	}
+}
+
+// Load 用一批API Key覆盖当前存储的全部配置
+func (s *APIKeyStore) Load(keys []APIKey) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.keys = make(map[string]*APIKey, len(keys))
+	s.limiters = make(map[string]*rateLimiter, len(keys))
+	for i := range keys {
+		key := keys[i]
+		s.keys[key.ID] = &key
+		s.limiters[key.ID] = newRateLimiter(key.RateLimit)
+	}
+
+	logger.Info("加载下游API Key", logger.Int("数量", len(keys)))
+}
+
+// Get 按ID查找API Key
+func (s *APIKeyStore) Get(id string) (*APIKey, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	key, exists := s.keys[id]
+	if !exists {
+		return nil, fmt.Errorf("未知的API Key: %s", id)
+	}
+	return key, nil
+}
+
+// Authenticate 按密钥原文查找匹配的API Key，使用常量时间比较防止时序攻击
+func (s *APIKeyStore) Authenticate(secret string) (*APIKey, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, key := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(key.Secret), []byte(secret)) == 1 {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("无效的API Key")
+}
+
+// Allow 判断该API Key当前是否允许发起一次请求（速率限制 + 月度额度）
+func (s *APIKeyStore) Allow(id string) bool {
+	s.mutex.RLock()
+	key, exists := s.keys[id]
+	limiter := s.limiters[id]
+	s.mutex.RUnlock()
+	if !exists {
+		return false
+	}
+
+	if limiter != nil && !limiter.Allow() {
+		return false
+	}
+
+	if key.MonthlyCreditCap <= 0 {
+		return true
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	currentMonth := monthLabel(time.Now())
+	usage := s.usage[id]
+	if usage.month != currentMonth {
+		usage = apiKeyUsage{month: currentMonth}
+	}
+
+	return usage.used < key.MonthlyCreditCap
+}
+
+// RecordUsage 记录该API Key本次消耗的额度，用于月度额度累计
+func (s *APIKeyStore) RecordUsage(id string, credits float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	currentMonth := monthLabel(time.Now())
+	usage := s.usage[id]
+	if usage.month != currentMonth {
+		usage = apiKeyUsage{month: currentMonth}
+	}
+	usage.used += credits
+	s.usage[id] = usage
+}
+
+func monthLabel(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// LoadAPIKeysFromJSON 从JSON文件加载下游API Key配置
+func LoadAPIKeysFromJSON(filePath string) ([]APIKey, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取API Key配置文件失败: %w", err)
+	}
+
+	var keys []APIKey
+	if err := json.Unmarshal(content, &keys); err != nil {
+		return nil, fmt.Errorf("解析API Key配置失败: %w", err)
+	}
+
+	logger.Info("从JSON加载API Key", logger.Int("数量", len(keys)))
+	return keys, nil
+}
+
+// LoadAPIKeysFromCSV 从CSV文件加载下游API Key配置
+// 列: id,secret,allowedAccounts(分号分隔，可留空),rateLimit,monthlyCreditCap
+func LoadAPIKeysFromCSV(filePath string) ([]APIKey, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开API Key CSV文件失败: %w", err)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("读取API Key CSV文件失败: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("API Key CSV文件为空或缺少数据行")
+	}
+
+	var keys []APIKey
+	for i, record := range records[1:] {
+		if len(record) < 2 {
+			logger.Warn("跳过无效的API Key CSV行", logger.Int("行号", i+2))
+			continue
+		}
+
+		var allowedAccounts []string
+		if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+			for _, acc := range strings.Split(record[2], ";") {
+				if trimmed := strings.TrimSpace(acc); trimmed != "" {
+					allowedAccounts = append(allowedAccounts, trimmed)
+				}
+			}
+		}
+
+		var rateLimit float64
+		if len(record) > 3 {
+			rateLimit, _ = strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+		}
+
+		var monthlyCap float64
+		if len(record) > 4 {
+			monthlyCap, _ = strconv.ParseFloat(strings.TrimSpace(record[4]), 64)
+		}
+
+		keys = append(keys, APIKey{
+			ID:               strings.TrimSpace(record[0]),
+			Secret:           strings.TrimSpace(record[1]),
+			AllowedAccounts:  allowedAccounts,
+			RateLimit:        rateLimit,
+			MonthlyCreditCap: monthlyCap,
+		})
+	}
+
+	logger.Info("从CSV加载API Key", logger.Int("数量", len(keys)))
+	return keys, nil
+}
+
+// jwtClaims 支持的JWT payload字段子集
+type jwtClaims struct {
+	Sub   string `json:"sub"`
+	Exp   int64  `json:"exp,omitempty"`
+	Scope string `json:"scope,omitempty"`
+}
+
+// authenticateRequest 解析Authorization头，支持"Bearer <api-key密钥原文>"与"Bearer <HS256 JWT>"两种形式
+func (s *APIKeyStore) authenticateRequest(authorizationHeader string) (*APIKey, error) {
+	const prefix = "Bearer "
+	if !strings.HasPrefix(authorizationHeader, prefix) {
+		return nil, fmt.Errorf("缺少或无效的Authorization头")
+	}
+
+	token := strings.TrimPrefix(authorizationHeader, prefix)
+	if strings.Count(token, ".") == 2 {
+		return s.authenticateJWT(token)
+	}
+
+	return s.Authenticate(token)
+}
+
+// authenticateJWT 校验一个HS256签名的JWT：sub必须是已知的API Key ID，
+// 签名使用该API Key的Secret作为HMAC密钥，exp（若存在）必须晚于当前时间
+func (s *APIKeyStore) authenticateJWT(token string) (*APIKey, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("JWT格式无效")
+	}
+
+	payloadRaw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("解析JWT payload失败: %w", err)
+	}
+
+	var claims jwtClaims
+	if err := json.Unmarshal(payloadRaw, &claims); err != nil {
+		return nil, fmt.Errorf("解析JWT claims失败: %w", err)
+	}
+
+	key, err := s.Get(claims.Sub)
+	if err != nil {
+		return nil, fmt.Errorf("JWT sub对应的API Key不存在: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("解析JWT签名失败: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(key.Secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, fmt.Errorf("JWT签名校验失败")
+	}
+
+	if claims.Exp > 0 && time.Now().Unix() > claims.Exp {
+		return nil, fmt.Errorf("JWT已过期")
+	}
+
+	return key, nil
+}
+
+// apiKeyContextKey 用于在请求context中传递鉴权通过的*APIKey
+type apiKeyContextKey struct{}
+
+// APIKeyFromContext 从请求context中取出经Middleware鉴权通过的*APIKey
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	return key, ok
+}
+
+// Middleware 校验请求的Authorization头（Bearer <密钥原文> 或 HS256 JWT），
+// 鉴权失败返回401，超出限流/月度额度返回429，成功后将*APIKey放入context传给下游handler
+func (s *APIKeyStore) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey, err := s.authenticateRequest(r.Header.Get("Authorization"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		if !s.Allow(apiKey.ID) {
+			http.Error(w, "请求超出限流或月度额度", http.StatusTooManyRequests)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), apiKeyContextKey{}, apiKey)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// signHS256 按HS256签发一个测试用JWT，便于独立于生产代码验证authenticateJWT
+func signHS256(t *testing.T, payload map[string]any, secret string) string {
+	t.Helper()
+
+	headerJSON, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		t.Fatalf("序列化header失败: %v", err)
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("序列化payload失败: %v", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payloadJSON)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+	signature := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	return signingInput + "." + signature
+}
+
+func TestAuthenticateJWT_ValidToken(t *testing.T) {
+	store := NewAPIKeyStore()
+	store.Load([]APIKey{{ID: "key-1", Secret: "s3cr3t"}})
+
+	token := signHS256(t, map[string]any{
+		"sub": "key-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}, "s3cr3t")
+
+	key, err := store.authenticateJWT(token)
+	if err != nil {
+		t.Fatalf("authenticateJWT返回错误: %v", err)
+	}
+	if key.ID != "key-1" {
+		t.Fatalf("期望鉴权出key-1，实际为%s", key.ID)
+	}
+}
+
+func TestAuthenticateJWT_ExpiredTokenRejected(t *testing.T) {
+	store := NewAPIKeyStore()
+	store.Load([]APIKey{{ID: "key-1", Secret: "s3cr3t"}})
+
+	token := signHS256(t, map[string]any{
+		"sub": "key-1",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	}, "s3cr3t")
+
+	if _, err := store.authenticateJWT(token); err == nil {
+		t.Fatalf("期望已过期的JWT被拒绝")
+	}
+}
+
+func TestAuthenticateJWT_WrongSecretRejected(t *testing.T) {
+	store := NewAPIKeyStore()
+	store.Load([]APIKey{{ID: "key-1", Secret: "s3cr3t"}})
+
+	token := signHS256(t, map[string]any{
+		"sub": "key-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}, "wrong-secret")
+
+	if _, err := store.authenticateJWT(token); err == nil {
+		t.Fatalf("期望签名不匹配的JWT被拒绝")
+	}
+}
+
+func TestAuthenticateJWT_UnknownSubjectRejected(t *testing.T) {
+	store := NewAPIKeyStore()
+	store.Load([]APIKey{{ID: "key-1", Secret: "s3cr3t"}})
+
+	token := signHS256(t, map[string]any{
+		"sub": "no-such-key",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}, "s3cr3t")
+
+	if _, err := store.authenticateJWT(token); err == nil {
+		t.Fatalf("期望sub不存在的JWT被拒绝")
+	}
+}
@@ -10,6 +10,7 @@ import (
 type AuthService struct {
 	tokenManager *TokenManager
 	configs      []AuthConfig
+	apiKeys      *APIKeyStore
 }
 
 // NewAuthService 创建新的认证服务（推荐使用此方法而不是全局函数）
@@ -63,11 +64,76 @@ func (as *AuthService) GetToken() (types.TokenInfo, error) {
 	return as.tokenManager.getBestToken()
 }
 
+// ReportTokenResult 上报某个token的上游使用结果
+// 调用方在收到Kiro的401/AccessTokenExpired/InvalidGrant响应后调用此方法，
+// 换取一个新token并重试一次，典型用法：
+//
+//	newToken, err := authService.ReportTokenResult(usedToken, upstreamErr)
+//	if err == nil { /* 用newToken重放一次请求 */ }
+func (as *AuthService) ReportTokenResult(token types.TokenInfo, upstreamErr error) (types.TokenInfo, error) {
+	if as.tokenManager == nil {
+		return types.TokenInfo{}, fmt.Errorf("token管理器未初始化")
+	}
+	return as.tokenManager.ReportTokenResult(token, upstreamErr)
+}
+
+// GetTokenForSession 获取token时携带一个会话/请求key
+// 配合StickyBySession选择策略，使同一会话在其生命周期内稳定落在同一账号上
+func (as *AuthService) GetTokenForSession(sessionKey string) (types.TokenInfo, error) {
+	if as.tokenManager == nil {
+		return types.TokenInfo{}, fmt.Errorf("token管理器未初始化")
+	}
+	return as.tokenManager.GetBestTokenForSession(sessionKey)
+}
+
 // GetTokenManager 获取底层的TokenManager（用于高级操作）
 func (as *AuthService) GetTokenManager() *TokenManager {
 	return as.tokenManager
 }
 
+// EnableAPIKeyAuth 启用下游API Key鉴权：加载一批API Key，并让TokenManager
+// 按subject（API Key的ID）过滤可用账号范围，实现"一个key只能用自己名下的账号"
+func (as *AuthService) EnableAPIKeyAuth(apiKeys []APIKey) {
+	store := NewAPIKeyStore()
+	store.Load(apiKeys)
+	as.apiKeys = store
+
+	as.tokenManager.SetAccountFilter(func(subject string) ([]string, bool) {
+		key, err := store.Get(subject)
+		if err != nil {
+			return nil, false
+		}
+		return key.AllowedAccounts, true
+	})
+}
+
+// GetAPIKeyStore 获取下游API Key存储（用于注册鉴权中间件），未调用EnableAPIKeyAuth时为nil
+func (as *AuthService) GetAPIKeyStore() *APIKeyStore {
+	return as.apiKeys
+}
+
+// GetTokenForAPIKey 为已通过鉴权的API Key获取一个其允许范围内的可用token
+// 每次成功签发都会把1个信用计入该API Key的月度用量，与上游账号Available的扣减保持同步
+func (as *AuthService) GetTokenForAPIKey(apiKey *APIKey) (types.TokenInfo, error) {
+	if as.tokenManager == nil {
+		return types.TokenInfo{}, fmt.Errorf("token管理器未初始化")
+	}
+	if apiKey == nil {
+		return types.TokenInfo{}, fmt.Errorf("API Key不能为空")
+	}
+
+	token, err := as.tokenManager.GetBestTokenFor(apiKey.ID)
+	if err != nil {
+		return types.TokenInfo{}, err
+	}
+
+	if as.apiKeys != nil {
+		as.apiKeys.RecordUsage(apiKey.ID, 1)
+	}
+
+	return token, nil
+}
+
 // GetConfigs 获取认证配置
 func (as *AuthService) GetConfigs() []AuthConfig {
 	return as.configs
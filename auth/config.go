@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
@@ -12,11 +13,13 @@ import (
 
 // AuthConfig 简化的认证配置
 type AuthConfig struct {
-	AuthType     string `json:"auth"`
-	RefreshToken string `json:"refreshToken"`
-	ClientID     string `json:"clientId,omitempty"`
-	ClientSecret string `json:"clientSecret,omitempty"`
-	Disabled     bool   `json:"disabled,omitempty"`
+	ID           string                    `json:"id,omitempty"` // 账号标识，供APIKey.AllowedAccounts引用；为空时默认使用其cache key（如"account-0"）
+	AuthType     string                    `json:"auth"`
+	RefreshToken string                    `json:"refreshToken"`
+	ClientID     string                    `json:"clientId,omitempty"`
+	ClientSecret string                    `json:"clientSecret,omitempty"`
+	Disabled     bool                      `json:"disabled,omitempty"`
+	Provider     *CredentialProviderConfig `json:"provider,omitempty"` // 动态凭证提供方，配置后RefreshToken由provider定期轮换
 }
 
 // 认证方法常量
@@ -71,6 +74,14 @@ func loadConfigs() ([]AuthConfig, error) {
 		logger.Debug("从环境变量加载JSON配置")
 	}
 
+	// 整个文件/字符串也可能被加密为单个信封（whole-file variant），先尝试解密
+	if decrypted, err := decryptWholeFileIfEnvelope([]byte(configData)); err != nil {
+		return nil, fmt.Errorf("解密KIRO_AUTH_TOKEN失败: %w", err)
+	} else if string(decrypted) != configData {
+		logger.Info("检测到加密的整体配置，已解密")
+		configData = string(decrypted)
+	}
+
 	// 解析JSON配置
 	configs, err := parseJSONConfig(configData)
 	if err != nil {
@@ -117,16 +128,45 @@ func parseJSONConfig(jsonData string) ([]AuthConfig, error) {
 		configs = []AuthConfig{single}
 	}
 
+	// refreshToken/clientSecret各自也可能是加密信封（单字段加密），在校验前透明解密
+	for i := range configs {
+		decrypted, err := decryptSecretFields(configs[i])
+		if err != nil {
+			return nil, fmt.Errorf("解密第%d项配置失败: %w", i+1, err)
+		}
+		configs[i] = decrypted
+	}
+
 	return configs, nil
 }
 
+// decryptSecretFields 透明解密cfg中可能被加密的refreshToken/clientSecret字段
+func decryptSecretFields(cfg AuthConfig) (AuthConfig, error) {
+	refreshToken, err := decryptFieldIfEnvelope(cfg.RefreshToken)
+	if err != nil {
+		return cfg, fmt.Errorf("解密refreshToken失败: %w", err)
+	}
+	cfg.RefreshToken = refreshToken
+
+	if cfg.ClientSecret != "" {
+		clientSecret, err := decryptFieldIfEnvelope(cfg.ClientSecret)
+		if err != nil {
+			return cfg, fmt.Errorf("解密clientSecret失败: %w", err)
+		}
+		cfg.ClientSecret = clientSecret
+	}
+
+	return cfg, nil
+}
+
 // processConfigs 处理和验证配置
 func processConfigs(configs []AuthConfig) []AuthConfig {
 	var validConfigs []AuthConfig
 
 	for i, config := range configs {
-		// 验证必要字段
-		if config.RefreshToken == "" {
+		// 验证必要字段；配置了Provider的账号由provider在首次轮询时填充refreshToken/密钥，
+		// 此时静态字段本就为空，不能按缺失字段处理
+		if config.RefreshToken == "" && config.Provider == nil {
 			continue
 		}
 
@@ -135,8 +175,8 @@ func processConfigs(configs []AuthConfig) []AuthConfig {
 			config.AuthType = AuthMethodSocial
 		}
 
-		// 验证IdC认证的必要字段
-		if config.AuthType == AuthMethodIdC {
+		// 验证IdC认证的必要字段（同样豁免Provider账号）
+		if config.AuthType == AuthMethodIdC && config.Provider == nil {
 			if config.ClientID == "" || config.ClientSecret == "" {
 				continue
 			}
@@ -156,13 +196,21 @@ func processConfigs(configs []AuthConfig) []AuthConfig {
 
 // LoadAccountsFromCSV 从CSV文件加载账号配置
 func LoadAccountsFromCSV(filePath string) ([]AuthConfig, error) {
-	file, err := os.Open(filePath)
+	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("打开CSV文件失败: %w", err)
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
+	// 整个CSV文件也可能被加密为单个信封（whole-file variant），先尝试解密
+	decrypted, err := decryptWholeFileIfEnvelope(content)
+	if err != nil {
+		return nil, fmt.Errorf("解密CSV文件失败: %w", err)
+	}
+	if !bytes.Equal(decrypted, content) {
+		logger.Info("检测到加密的CSV文件，已解密", logger.String("文件路径", filePath))
+	}
+
+	reader := csv.NewReader(bytes.NewReader(decrypted))
 	records, err := reader.ReadAll()
 	if err != nil {
 		return nil, fmt.Errorf("读取CSV文件失败: %w", err)
@@ -184,11 +232,21 @@ func LoadAccountsFromCSV(filePath string) ([]AuthConfig, error) {
 			continue
 		}
 
+		// refreshToken/clientSecret单元格也可能是加密信封，校验前透明解密
+		refreshToken, err := decryptFieldIfEnvelope(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("解密第%d行refreshToken失败: %w", i+2, err)
+		}
+		clientSecret, err := decryptFieldIfEnvelope(strings.TrimSpace(record[3]))
+		if err != nil {
+			return nil, fmt.Errorf("解密第%d行clientSecret失败: %w", i+2, err)
+		}
+
 		configs = append(configs, AuthConfig{
 			AuthType:     AuthMethodIdC,
-			RefreshToken: strings.TrimSpace(record[1]),
+			RefreshToken: refreshToken,
 			ClientID:     strings.TrimSpace(record[2]),
-			ClientSecret: strings.TrimSpace(record[3]),
+			ClientSecret: clientSecret,
 		})
 	}
 
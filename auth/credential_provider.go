@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"kiro2api/logger"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// CredentialProviderConfig 描述一个动态凭证提供方，写在AuthConfig.Provider里
+// 例如：{"auth":"Social","provider":{"type":"http","url":"https://vault.internal/kiro/token","refreshEvery":"10m"}}
+// 用于从中心化的密钥管理/令牌下发服务轮转refreshToken，而不是把它写死在静态配置里
+type CredentialProviderConfig struct {
+	Type         string            `json:"type"`                   // http | exec | file-watch
+	URL          string            `json:"url,omitempty"`          // type=http 时的拉取地址
+	Headers      map[string]string `json:"headers,omitempty"`      // type=http 时附加的请求头
+	Command      string            `json:"command,omitempty"`      // type=exec 时执行的命令
+	Args         []string          `json:"args,omitempty"`         // type=exec 时的命令参数
+	Path         string            `json:"path,omitempty"`         // type=file-watch 时监视的文件路径
+	RefreshEvery string            `json:"refreshEvery,omitempty"` // 轮询间隔，如"10m"，默认defaultProviderRefreshInterval
+}
+
+// 凭证提供方类型标识，对应CredentialProviderConfig.Type的取值
+const (
+	ProviderTypeHTTP      = "http"
+	ProviderTypeExec      = "exec"
+	ProviderTypeFileWatch = "file-watch"
+)
+
+// defaultProviderRefreshInterval 未指定refreshEvery时的默认轮询间隔
+const defaultProviderRefreshInterval = 5 * time.Minute
+
+// credentialPayload 凭证提供方返回的JSON结构
+type credentialPayload struct {
+	RefreshToken string `json:"refreshToken"`
+	ClientID     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+}
+
+// CredentialProvider 按需拉取最新凭证，TokenManager会按RefreshEvery周期性调用Fetch
+type CredentialProvider interface {
+	// Fetch 拉取一份最新凭证
+	Fetch(ctx context.Context) (credentialPayload, error)
+	// Type 返回供应方类型标识，用于日志
+	Type() string
+}
+
+// WatchableCredentialProvider 是CredentialProvider的可选扩展：不依赖固定轮询间隔，
+// 而是在底层数据源发生变化时主动触发onChange（如本地凭证文件的inotify写入事件），
+// 只有FileWatchCredentialProvider这类有原生变更通知机制的供应方才需要实现它
+type WatchableCredentialProvider interface {
+	CredentialProvider
+	// Watch 阻塞运行直到ctx被取消；每当检测到凭证可能已变化时调用onChange触发一次Fetch
+	Watch(ctx context.Context, onChange func())
+}
+
+// NewCredentialProvider 按配置创建对应的CredentialProvider实现
+func NewCredentialProvider(cfg CredentialProviderConfig) (CredentialProvider, error) {
+	switch cfg.Type {
+	case ProviderTypeHTTP:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("http类型的provider缺少url")
+		}
+		return &HTTPCredentialProvider{URL: cfg.URL, Headers: cfg.Headers, Client: http.DefaultClient}, nil
+	case ProviderTypeExec:
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("exec类型的provider缺少command")
+		}
+		return &ExecCredentialProvider{Command: cfg.Command, Args: cfg.Args}, nil
+	case ProviderTypeFileWatch:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("file-watch类型的provider缺少path")
+		}
+		return &FileWatchCredentialProvider{Path: cfg.Path}, nil
+	default:
+		return nil, fmt.Errorf("不支持的provider类型: %s", cfg.Type)
+	}
+}
+
+// parseRefreshEvery 解析RefreshEvery，格式无效或未设置时回退到默认轮询间隔
+func parseRefreshEvery(raw string) time.Duration {
+	if raw == "" {
+		return defaultProviderRefreshInterval
+	}
+
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval <= 0 {
+		return defaultProviderRefreshInterval
+	}
+
+	return interval
+}
+
+// HTTPCredentialProvider 周期性GET一个HTTP端点获取最新凭证，类似AWS IMDS的凭证下发方式
+type HTTPCredentialProvider struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+func (p *HTTPCredentialProvider) Type() string { return ProviderTypeHTTP }
+
+func (p *HTTPCredentialProvider) Fetch(ctx context.Context) (credentialPayload, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return credentialPayload{}, fmt.Errorf("构造凭证请求失败: %w", err)
+	}
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return credentialPayload{}, fmt.Errorf("请求凭证端点失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return credentialPayload{}, fmt.Errorf("凭证端点返回非200状态码: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return credentialPayload{}, fmt.Errorf("读取凭证响应失败: %w", err)
+	}
+
+	return decodeCredentialPayload(body)
+}
+
+// ExecCredentialProvider 执行外部命令获取最新凭证，命令的stdout需为credentialPayload的JSON
+type ExecCredentialProvider struct {
+	Command string
+	Args    []string
+}
+
+func (p *ExecCredentialProvider) Type() string { return ProviderTypeExec }
+
+func (p *ExecCredentialProvider) Fetch(ctx context.Context) (credentialPayload, error) {
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return credentialPayload{}, fmt.Errorf("执行凭证命令失败: %w", err)
+	}
+
+	return decodeCredentialPayload(output)
+}
+
+// FileWatchCredentialProvider 通过inotify监视本地文件，文件内容变化
+// （如被密钥分发agent重写）后立即重读，而不是按固定间隔轮询
+type FileWatchCredentialProvider struct {
+	Path string
+}
+
+func (p *FileWatchCredentialProvider) Type() string { return ProviderTypeFileWatch }
+
+func (p *FileWatchCredentialProvider) Fetch(_ context.Context) (credentialPayload, error) {
+	content, err := os.ReadFile(p.Path)
+	if err != nil {
+		return credentialPayload{}, fmt.Errorf("读取凭证文件失败: %w", err)
+	}
+
+	return decodeCredentialPayload(content)
+}
+
+// Watch 监视凭证文件所在目录（而非文件本身），以兼容"先写临时文件再rename"这种
+// 密钥分发agent常用的原子替换手法——直接监视文件本身在rename场景下会丢失后续事件
+func (p *FileWatchCredentialProvider) Watch(ctx context.Context, onChange func()) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Warn("创建凭证文件监视器失败，凭证轮换将不会被感知", logger.String("path", p.Path), logger.Err(err))
+		return
+	}
+	defer watcher.Close()
+
+	dir := filepath.Dir(p.Path)
+	if err := watcher.Add(dir); err != nil {
+		logger.Warn("监视凭证文件目录失败", logger.String("path", p.Path), logger.Err(err))
+		return
+	}
+
+	target := filepath.Clean(p.Path)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != target {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			onChange()
+		case watchErr, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warn("监视凭证文件出错", logger.String("path", p.Path), logger.Err(watchErr))
+		}
+	}
+}
+
+func decodeCredentialPayload(data []byte) (credentialPayload, error) {
+	var payload credentialPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return credentialPayload{}, fmt.Errorf("解析凭证JSON失败: %w", err)
+	}
+	if strings.TrimSpace(payload.RefreshToken) == "" {
+		return credentialPayload{}, fmt.Errorf("凭证响应缺少refreshToken字段")
+	}
+
+	return payload, nil
+}
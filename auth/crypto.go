@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// 支持的加密算法标识，与ConfigEnvelope.Algorithm的取值一一对应
+const (
+	AlgorithmAES256GCM        = "AES-256-GCM"
+	AlgorithmChaCha20Poly1305 = "ChaCha20-Poly1305"
+)
+
+// ConfigEnvelope 加密信封，既可以包裹单个字段（如refreshToken），也可以包裹整个配置文件
+// Ciphertext/Nonce/AAD均为标准base64编码
+type ConfigEnvelope struct {
+	Algorithm  string `json:"algorithm"`
+	Kid        string `json:"kid"`
+	Ciphertext string `json:"ciphertext"`
+	Nonce      string `json:"nonce"`
+	AAD        string `json:"aad,omitempty"`
+}
+
+// ConfigCipher 配置加解密算法的统一接口，便于后续新增算法或替换实现
+type ConfigCipher interface {
+	// Algorithm 返回该实现对应的算法标识，需与信封中的"algorithm"字段一致
+	Algorithm() string
+	// Encrypt 加密plaintext，返回密文与nonce（原始字节，调用方负责base64编码）
+	Encrypt(key, plaintext, aad []byte) (ciphertext, nonce []byte, err error)
+	// Decrypt 使用key/nonce/aad解密ciphertext
+	Decrypt(key, ciphertext, nonce, aad []byte) (plaintext []byte, err error)
+}
+
+// configCiphers 已注册的算法实现，按algorithm标识索引
+var configCiphers = map[string]ConfigCipher{
+	AlgorithmAES256GCM:        AES256GCMCipher{},
+	AlgorithmChaCha20Poly1305: ChaCha20Poly1305Cipher{},
+}
+
+// cipherForAlgorithm 按algorithm标识查找对应的ConfigCipher实现
+func cipherForAlgorithm(algorithm string) (ConfigCipher, error) {
+	impl, ok := configCiphers[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("不支持的加密算法: %s", algorithm)
+	}
+	return impl, nil
+}
+
+// AES256GCMCipher 基于AES-256-GCM的ConfigCipher实现
+type AES256GCMCipher struct{}
+
+func (AES256GCMCipher) Algorithm() string { return AlgorithmAES256GCM }
+
+func (AES256GCMCipher) Encrypt(key, plaintext, aad []byte) ([]byte, []byte, error) {
+	gcm, err := newAESGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("生成nonce失败: %w", err)
+	}
+
+	return gcm.Seal(nil, nonce, plaintext, aad), nonce, nil
+}
+
+func (AES256GCMCipher) Decrypt(key, ciphertext, nonce, aad []byte) ([]byte, error) {
+	gcm, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("AES-256-GCM解密失败: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("无效的AES密钥: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("初始化GCM失败: %w", err)
+	}
+
+	return gcm, nil
+}
+
+// ChaCha20Poly1305Cipher 基于ChaCha20-Poly1305的ConfigCipher实现
+type ChaCha20Poly1305Cipher struct{}
+
+func (ChaCha20Poly1305Cipher) Algorithm() string { return AlgorithmChaCha20Poly1305 }
+
+func (ChaCha20Poly1305Cipher) Encrypt(key, plaintext, aad []byte) ([]byte, []byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("无效的ChaCha20-Poly1305密钥: %w", err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("生成nonce失败: %w", err)
+	}
+
+	return aead.Seal(nil, nonce, plaintext, aad), nonce, nil
+}
+
+func (ChaCha20Poly1305Cipher) Decrypt(key, ciphertext, nonce, aad []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, fmt.Errorf("无效的ChaCha20-Poly1305密钥: %w", err)
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("ChaCha20-Poly1305解密失败: %w", err)
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"kiro2api/logger"
+)
+
+// EncryptConfigOptions 是 `kiro2api encrypt-config` 子命令的参数
+type EncryptConfigOptions struct {
+	InputPath  string // 明文JSON或CSV配置文件路径
+	OutputPath string // 加密后文件的输出路径
+	Algorithm  string // 加密算法，默认AlgorithmAES256GCM
+	Kid        string // 密钥标识，决定使用哪个密钥加密
+}
+
+// RunEncryptConfig 读取明文JSON/CSV配置文件，整体加密为whole-file信封并写入OutputPath
+// 支撑 `kiro2api encrypt-config` 子命令，帮助用户把现有明文KIRO_AUTH_TOKEN/accounts.csv
+// 迁移为加密形式，迁移后原样指向新文件即可，loadConfigs/LoadAccountsFromCSV会透明解密
+func RunEncryptConfig(opts EncryptConfigOptions) error {
+	if opts.InputPath == "" || opts.OutputPath == "" {
+		return fmt.Errorf("必须同时指定输入和输出文件路径")
+	}
+	if opts.Kid == "" {
+		return fmt.Errorf("必须指定kid以便后续密钥轮换")
+	}
+	if opts.Algorithm == "" {
+		opts.Algorithm = AlgorithmAES256GCM
+	}
+
+	plaintext, err := os.ReadFile(opts.InputPath)
+	if err != nil {
+		return fmt.Errorf("读取明文配置失败: %w", err)
+	}
+
+	envelope, err := EncryptEnvelope(opts.Algorithm, opts.Kid, plaintext, nil)
+	if err != nil {
+		return fmt.Errorf("加密配置失败: %w", err)
+	}
+
+	encoded, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化加密信封失败: %w", err)
+	}
+
+	if err := os.WriteFile(opts.OutputPath, encoded, 0o600); err != nil {
+		return fmt.Errorf("写入加密配置失败: %w", err)
+	}
+
+	logger.Info("配置已加密",
+		logger.String("算法", opts.Algorithm),
+		logger.String("kid", opts.Kid),
+		logger.String("输出路径", opts.OutputPath))
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// decryptEnvelope 使用全局密钥提供方解密信封，返回明文字节
+func decryptEnvelope(envelope ConfigEnvelope) ([]byte, error) {
+	cipherImpl, err := cipherForAlgorithm(envelope.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := defaultKeyProvider.GetKey(envelope.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("获取kid=%s对应密钥失败: %w", envelope.Kid, err)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(envelope.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("ciphertext不是合法的base64: %w", err)
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(envelope.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("nonce不是合法的base64: %w", err)
+	}
+
+	var aad []byte
+	if envelope.AAD != "" {
+		aad, err = base64.StdEncoding.DecodeString(envelope.AAD)
+		if err != nil {
+			return nil, fmt.Errorf("aad不是合法的base64: %w", err)
+		}
+	}
+
+	return cipherImpl.Decrypt(key, ciphertext, nonce, aad)
+}
+
+// EncryptEnvelope 使用指定算法/kid加密plaintext，生成信封
+// 供encrypt-config子命令将明文配置迁移为加密形式
+func EncryptEnvelope(algorithm, kid string, plaintext, aad []byte) (ConfigEnvelope, error) {
+	cipherImpl, err := cipherForAlgorithm(algorithm)
+	if err != nil {
+		return ConfigEnvelope{}, err
+	}
+
+	key, err := defaultKeyProvider.GetKey(kid)
+	if err != nil {
+		return ConfigEnvelope{}, fmt.Errorf("获取kid=%s对应密钥失败: %w", kid, err)
+	}
+
+	ciphertext, nonce, err := cipherImpl.Encrypt(key, plaintext, aad)
+	if err != nil {
+		return ConfigEnvelope{}, err
+	}
+
+	envelope := ConfigEnvelope{
+		Algorithm:  algorithm,
+		Kid:        kid,
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+	}
+	if len(aad) > 0 {
+		envelope.AAD = base64.StdEncoding.EncodeToString(aad)
+	}
+
+	return envelope, nil
+}
+
+// decryptFieldIfEnvelope 判断raw本身是否是JSON编码的加密信封，是则解密返回明文，否则原样返回
+// 用于让refreshToken/clientSecret等单个字段支持加密存储
+func decryptFieldIfEnvelope(raw string) (string, error) {
+	envelope, ok := parseEnvelope(raw)
+	if !ok {
+		return raw, nil
+	}
+
+	plaintext, err := decryptEnvelope(envelope)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// decryptWholeFileIfEnvelope 判断content整体是否是一个加密信封（whole-file variant），是则解密返回明文
+func decryptWholeFileIfEnvelope(content []byte) ([]byte, error) {
+	envelope, ok := parseEnvelope(string(content))
+	if !ok {
+		return content, nil
+	}
+
+	return decryptEnvelope(envelope)
+}
+
+// parseEnvelope 尝试把raw解析为ConfigEnvelope，仅当其是JSON对象且algorithm字段非空时才视为信封
+func parseEnvelope(raw string) (ConfigEnvelope, bool) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" || trimmed[0] != '{' {
+		return ConfigEnvelope{}, false
+	}
+
+	var envelope ConfigEnvelope
+	if err := json.Unmarshal([]byte(trimmed), &envelope); err != nil || envelope.Algorithm == "" {
+		return ConfigEnvelope{}, false
+	}
+
+	return envelope, true
+}
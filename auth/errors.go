@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// UpstreamStatusError 描述一次上游(Kiro)HTTP调用的结构化结果
+// 调用方应在解析响应时构造它（StatusCode来自实际的HTTP状态码，Code来自响应体的业务错误码），
+// 而不是把状态码/错误文本拼进普通error——避免"401"这类数字子串误命中无关错误
+type UpstreamStatusError struct {
+	StatusCode int    // 实际HTTP状态码，如401
+	Code       string // 业务错误码，如AccessTokenExpired/InvalidGrant
+	Err        error  // 原始错误（可选）
+}
+
+func (e *UpstreamStatusError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	if e.Code != "" {
+		return e.Code
+	}
+	return http.StatusText(e.StatusCode)
+}
+
+func (e *UpstreamStatusError) Unwrap() error { return e.Err }
+
+// authExpiredCodes Kiro专有的业务错误码，命中即视为token已失效
+// 这些是具体的驼峰/下划线标识符，不会像"401"/"Unauthorized"那样与无关错误文本碰撞
+var authExpiredCodes = []string{
+	"AccessTokenExpired",
+	"InvalidGrant",
+	"invalid_grant",
+}
+
+// IsAuthExpiredError 判断err是否表示上游因token失效而拒绝了请求
+// 优先判断结构化的*UpstreamStatusError（StatusCode==401或Code命中authExpiredCodes）；
+// 调用方未采用结构化错误时，兜底按authExpiredCodes中的业务错误码做子串匹配，
+// 但不再匹配裸的"401"/"Unauthorized"，避免误伤消息中偶然包含这些词的无关错误
+func IsAuthExpiredError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var statusErr *UpstreamStatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.StatusCode == http.StatusUnauthorized {
+			return true
+		}
+		return containsAny(statusErr.Code, authExpiredCodes)
+	}
+
+	return containsAny(err.Error(), authExpiredCodes)
+}
+
+func containsAny(s string, markers []string) bool {
+	for _, marker := range markers {
+		if marker != "" && strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
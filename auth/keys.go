@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// KeyProvider 按kid解析出对应的加密密钥
+// 允许将"密钥从哪里来"与"用哪种算法加解密"解耦，便于密钥轮换而不改动配置本身
+type KeyProvider interface {
+	GetKey(kid string) ([]byte, error)
+}
+
+// defaultKeyProvider 全局密钥提供方，默认从环境变量读取
+// 部署方可在进程启动时调用SetKeyProvider替换为文件或外部KMS来源
+var defaultKeyProvider KeyProvider = NewEnvKeyProvider("")
+
+// SetKeyProvider 替换全局密钥提供方
+func SetKeyProvider(provider KeyProvider) {
+	defaultKeyProvider = provider
+}
+
+// EnvKeyProvider 从环境变量读取密钥，变量名为 EnvPrefix+kid的大写形式
+// 例如 kid="main" 默认对应环境变量 KIRO_CONFIG_KEY_MAIN
+type EnvKeyProvider struct {
+	EnvPrefix string
+}
+
+// NewEnvKeyProvider 创建基于环境变量的KeyProvider，envPrefix为空时使用默认前缀
+func NewEnvKeyProvider(envPrefix string) *EnvKeyProvider {
+	if envPrefix == "" {
+		envPrefix = "KIRO_CONFIG_KEY_"
+	}
+	return &EnvKeyProvider{EnvPrefix: envPrefix}
+}
+
+func (p *EnvKeyProvider) GetKey(kid string) ([]byte, error) {
+	envVar := p.EnvPrefix + strings.ToUpper(kid)
+	encoded := os.Getenv(envVar)
+	if encoded == "" {
+		return nil, fmt.Errorf("未找到kid=%s对应的密钥环境变量: %s", kid, envVar)
+	}
+	return decodeKey(encoded)
+}
+
+// FileKeyProvider 从本地目录读取密钥文件，文件名即为kid
+type FileKeyProvider struct {
+	Dir string
+}
+
+// NewFileKeyProvider 创建基于密钥文件目录的KeyProvider
+func NewFileKeyProvider(dir string) *FileKeyProvider {
+	return &FileKeyProvider{Dir: dir}
+}
+
+func (p *FileKeyProvider) GetKey(kid string) ([]byte, error) {
+	content, err := os.ReadFile(filepath.Join(p.Dir, kid))
+	if err != nil {
+		return nil, fmt.Errorf("读取kid=%s对应的密钥文件失败: %w", kid, err)
+	}
+	return decodeKey(strings.TrimSpace(string(content)))
+}
+
+// ExecKeyProvider 通过外部KMS命令获取密钥，命令以kid作为最后一个参数调用，
+// 其标准输出（去除首尾空白后）即为base64编码的密钥
+type ExecKeyProvider struct {
+	Command string
+	Args    []string
+}
+
+// NewExecKeyProvider 创建基于外部命令（如KMS CLI）的KeyProvider
+func NewExecKeyProvider(command string, args ...string) *ExecKeyProvider {
+	return &ExecKeyProvider{Command: command, Args: args}
+}
+
+func (p *ExecKeyProvider) GetKey(kid string) ([]byte, error) {
+	cmd := exec.Command(p.Command, append(append([]string{}, p.Args...), kid)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("执行KMS命令获取kid=%s对应密钥失败: %w", kid, err)
+	}
+	return decodeKey(strings.TrimSpace(string(output)))
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("密钥必须是base64编码: %w", err)
+	}
+	return key, nil
+}
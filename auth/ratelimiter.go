@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter 简单的令牌桶限流器，rate<=0表示不限流
+type rateLimiter struct {
+	mutex      sync.Mutex
+	rate       float64 // 每秒生成的令牌数
+	capacity   float64 // 桶容量，即允许的瞬时突发量
+	tokens     float64
+	lastRefill time.Time
+}
+
+// newRateLimiter 创建限流器，突发容量与速率相同（即最多允许1秒的突发请求），
+// 但rate>0时容量至少为1，使每秒不足1次的速率也能按间隔放行请求
+func newRateLimiter(rate float64) *rateLimiter {
+	capacity := rate
+	if capacity <= 0 {
+		capacity = 0
+	} else if capacity < 1 {
+		capacity = 1
+	}
+	return &rateLimiter{
+		rate:       rate,
+		capacity:   capacity,
+		tokens:     capacity,
+		lastRefill: time.Now(),
+	}
+}
+
+// Allow 尝试消耗一个令牌，rate<=0时永远放行
+func (l *rateLimiter) Allow() bool {
+	if l.rate <= 0 {
+		return true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(l.lastRefill).Seconds()
+	l.lastRefill = now
+
+	l.tokens += elapsed * l.rate
+	if l.tokens > l.capacity {
+		l.tokens = l.capacity
+	}
+	if l.tokens < 1 {
+		return false
+	}
+
+	l.tokens--
+	return true
+}
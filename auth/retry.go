@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"kiro2api/types"
+)
+
+// DoWithTokenRetry 执行一次对Kiro的HTTP调用，并在响应状态码为401、或响应体中解析出的
+// 业务错误码为AccessTokenExpired/InvalidGrant时，通过ReportTokenResult换取一个新token、
+// 用buildRequest重建请求后重试恰好一次。
+//
+// buildRequest用传入的token构造一次请求（通常是设置Authorization头），每次调用前都会重新执行，
+// 以保证重试时请求体等一次性资源（如io.Reader）能被正确地重新构造。
+//
+// 典型用法：
+//
+//	resp, usedToken, err := authService.DoWithTokenRetry(httpClient, token, func(t types.TokenInfo) (*http.Request, error) {
+//		req, err := http.NewRequest(http.MethodPost, kiroURL, bytes.NewReader(payload))
+//		if err != nil { return nil, err }
+//		req.Header.Set("Authorization", "Bearer "+t.AccessToken)
+//		return req, nil
+//	})
+func (as *AuthService) DoWithTokenRetry(
+	client *http.Client,
+	token types.TokenInfo,
+	buildRequest func(token types.TokenInfo) (*http.Request, error),
+) (*http.Response, types.TokenInfo, error) {
+	resp, err := doOnce(client, token, buildRequest)
+	if err != nil {
+		return nil, token, fmt.Errorf("请求Kiro失败: %w", err)
+	}
+
+	upstreamErr, restoredResp, readErr := classifyUpstreamResponse(resp)
+	if readErr != nil {
+		return nil, token, fmt.Errorf("读取Kiro响应失败: %w", readErr)
+	}
+
+	if !IsAuthExpiredError(upstreamErr) {
+		return restoredResp, token, nil
+	}
+
+	newToken, reportErr := as.ReportTokenResult(token, upstreamErr)
+	if reportErr != nil {
+		return nil, token, fmt.Errorf("token失效后重新获取token失败: %w", reportErr)
+	}
+
+	resp, err = doOnce(client, newToken, buildRequest)
+	if err != nil {
+		return nil, newToken, fmt.Errorf("使用新token重试Kiro请求失败: %w", err)
+	}
+
+	return resp, newToken, nil
+}
+
+// kiroErrorBody Kiro错误响应体中常见的几种业务错误码字段，尽量兼容不同接口的命名
+type kiroErrorBody struct {
+	Error  string `json:"error"`
+	Reason string `json:"reason"`
+}
+
+// classifyUpstreamResponse 读出响应体（非401时也一并尝试解析业务错误码，
+// 例如Kiro可能用200/400包一层{"error":"AccessTokenExpired"}而不是裸401），
+// 构造*UpstreamStatusError用于IsAuthExpiredError判断，并把body封回resp供调用方正常读取
+func classifyUpstreamResponse(resp *http.Response) (*UpstreamStatusError, *http.Response, error) {
+	bodyBytes, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+
+	var parsed kiroErrorBody
+	_ = json.Unmarshal(bodyBytes, &parsed)
+	code := parsed.Error
+	if code == "" {
+		code = parsed.Reason
+	}
+
+	return &UpstreamStatusError{StatusCode: resp.StatusCode, Code: code}, resp, nil
+}
+
+func doOnce(
+	client *http.Client,
+	token types.TokenInfo,
+	buildRequest func(token types.TokenInfo) (*http.Request, error),
+) (*http.Response, error) {
+	req, err := buildRequest(token)
+	if err != nil {
+		return nil, fmt.Errorf("构造请求失败: %w", err)
+	}
+	return client.Do(req)
+}
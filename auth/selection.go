@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"kiro2api/logger"
+)
+
+// SelectionOutcome 描述一次token租用后的结果，供SelectionStrategy.OnResult更新自身状态
+type SelectionOutcome int
+
+const (
+	OutcomeSuccess SelectionOutcome = iota
+	OutcomeExhausted
+	OutcomeRefreshFailed
+)
+
+// 策略名称常量，对应strategyEnvVar等配置项的取值
+const (
+	StrategySequential        = "sequential"
+	StrategyWeightedAvailable = "weighted_available"
+	StrategyLeastRecentlyUsed = "least_recently_used"
+	StrategyHighestAvailable  = "highest_available"
+	StrategyStickyBySession   = "sticky_by_session"
+)
+
+// strategyEnvVar 用于配置token选择策略的环境变量名
+const strategyEnvVar = "KIRO_SELECTION_STRATEGY"
+
+// SelectionStrategy 定义如何从一组候选token中选出下一个使用的token
+// Pick只负责"选谁"，OnResult让策略记录选择结果以便调整后续行为；
+// 内置策略的OnResult均为空操作（LRU依赖的LastUsed由TokenManager维护）
+type SelectionStrategy interface {
+	// Name 策略名称，用于日志与配置匹配
+	Name() string
+	// Pick 从candidates中选出下一个要使用的token，candidates保证非空
+	Pick(candidates []*CachedToken) *CachedToken
+	// OnResult 上报某次选择的结果
+	OnResult(token *CachedToken, outcome SelectionOutcome)
+}
+
+// KeyedSelectionStrategy 是SelectionStrategy的可选扩展，支持按一个稳定的会话/请求key选择token
+// 只有StickyBySession这类需要"相同key稳定落在同一账号"的策略才需要实现它
+type KeyedSelectionStrategy interface {
+	SelectionStrategy
+	PickForKey(sessionKey string, candidates []*CachedToken) *CachedToken
+}
+
+// NewSelectionStrategy 按名称创建策略实例，未知名称时降级为Sequential
+func NewSelectionStrategy(name string) SelectionStrategy {
+	switch name {
+	case StrategyWeightedAvailable:
+		return NewWeightedByAvailable()
+	case StrategyLeastRecentlyUsed:
+		return &LeastRecentlyUsed{}
+	case StrategyHighestAvailable:
+		return &HighestAvailable{}
+	case StrategyStickyBySession:
+		return NewStickyBySession()
+	default:
+		return &Sequential{}
+	}
+}
+
+// loadStrategyFromEnv 按环境变量构造SelectionStrategy，未设置时使用Sequential（保持原有行为）
+func loadStrategyFromEnv() SelectionStrategy {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv(strategyEnvVar)))
+	if name == "" {
+		return &Sequential{}
+	}
+
+	strategy := NewSelectionStrategy(name)
+	logger.Info("已加载token选择策略", logger.String("策略", strategy.Name()))
+	return strategy
+}
+
+// Sequential 维持传统的顺序轮询行为：总是选择candidates中的第一个
+// TokenManager按configOrder从currentIndex开始收集candidates，因此"第一个"即"下一个该用的账号"
+type Sequential struct{}
+
+func (s *Sequential) Name() string { return StrategySequential }
+
+func (s *Sequential) Pick(candidates []*CachedToken) *CachedToken {
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[0]
+}
+
+func (s *Sequential) OnResult(*CachedToken, SelectionOutcome) {}
+
+// WeightedByAvailable 按Available占比做加权随机选择，Available越大的账号越容易被选中
+// 相比严格顺序，能让配额更均匀地分摊到所有账号，避免前几个账号被集中消耗
+type WeightedByAvailable struct {
+	rng *rand.Rand
+}
+
+// NewWeightedByAvailable 创建按Available加权的选择策略
+func NewWeightedByAvailable() *WeightedByAvailable {
+	return &WeightedByAvailable{rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (s *WeightedByAvailable) Name() string { return StrategyWeightedAvailable }
+
+func (s *WeightedByAvailable) Pick(candidates []*CachedToken) *CachedToken {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var total float64
+	for _, c := range candidates {
+		if c.Available > 0 {
+			total += c.Available
+		}
+	}
+	if total <= 0 {
+		return candidates[0]
+	}
+
+	r := s.rng.Float64() * total
+	for _, c := range candidates {
+		if c.Available <= 0 {
+			continue
+		}
+		r -= c.Available
+		if r <= 0 {
+			return c
+		}
+	}
+
+	return candidates[len(candidates)-1]
+}
+
+func (s *WeightedByAvailable) OnResult(*CachedToken, SelectionOutcome) {}
+
+// LeastRecentlyUsed 选择LastUsed最早（最久未被使用）的账号，使负载尽量均摊
+type LeastRecentlyUsed struct{}
+
+func (s *LeastRecentlyUsed) Name() string { return StrategyLeastRecentlyUsed }
+
+func (s *LeastRecentlyUsed) Pick(candidates []*CachedToken) *CachedToken {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.LastUsed.Before(best.LastUsed) {
+			best = c
+		}
+	}
+
+	return best
+}
+
+func (s *LeastRecentlyUsed) OnResult(*CachedToken, SelectionOutcome) {}
+
+// HighestAvailable 总是选择剩余配额Available最大的账号
+type HighestAvailable struct{}
+
+func (s *HighestAvailable) Name() string { return StrategyHighestAvailable }
+
+func (s *HighestAvailable) Pick(candidates []*CachedToken) *CachedToken {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.Available > best.Available {
+			best = c
+		}
+	}
+
+	return best
+}
+
+func (s *HighestAvailable) OnResult(*CachedToken, SelectionOutcome) {}
+
+// StickyBySession 让同一个session/request key在其生命周期内稳定落在同一账号上，
+// 常用于需要让多轮对话持续消耗同一账号信用额度的场景
+// 不带key调用时退化为HighestAvailable，避免在无法区分会话时选择行为不确定
+type StickyBySession struct {
+	fallback SelectionStrategy
+}
+
+// NewStickyBySession 创建会话粘性选择策略
+func NewStickyBySession() *StickyBySession {
+	return &StickyBySession{fallback: &HighestAvailable{}}
+}
+
+func (s *StickyBySession) Name() string { return StrategyStickyBySession }
+
+func (s *StickyBySession) Pick(candidates []*CachedToken) *CachedToken {
+	return s.fallback.Pick(candidates)
+}
+
+// PickForKey 按sessionKey的哈希值在candidates中稳定选出同一个账号
+func (s *StickyBySession) PickForKey(sessionKey string, candidates []*CachedToken) *CachedToken {
+	if sessionKey == "" || len(candidates) == 0 {
+		return s.fallback.Pick(candidates)
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(sessionKey))
+	sum := h.Sum64()
+
+	// 先按AccessToken排序，保证同一组candidates在多次调用间的下标是稳定的
+	sorted := make([]*CachedToken, len(candidates))
+	copy(sorted, candidates)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Token.AccessToken < sorted[j].Token.AccessToken
+	})
+
+	return sorted[sum%uint64(len(sorted))]
+}
+
+func (s *StickyBySession) OnResult(*CachedToken, SelectionOutcome) {}
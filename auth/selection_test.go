@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"testing"
+
+	"kiro2api/types"
+)
+
+func TestStickyBySession_PickForKey_StableForSameKey(t *testing.T) {
+	strategy := NewStickyBySession()
+	candidates := []*CachedToken{
+		{Token: types.TokenInfo{AccessToken: "a"}, Available: 1},
+		{Token: types.TokenInfo{AccessToken: "b"}, Available: 1},
+		{Token: types.TokenInfo{AccessToken: "c"}, Available: 1},
+	}
+
+	first := strategy.PickForKey("session-1", candidates)
+	for i := 0; i < 5; i++ {
+		got := strategy.PickForKey("session-1", candidates)
+		if got != first {
+			t.Fatalf("期望同一个sessionKey稳定选中同一账号，首次选中%s，第%d次选中%s",
+				first.Token.AccessToken, i, got.Token.AccessToken)
+		}
+	}
+}
+
+func TestStickyBySession_PickForKey_EmptyKeyFallsBackToHighestAvailable(t *testing.T) {
+	strategy := NewStickyBySession()
+	candidates := []*CachedToken{
+		{Token: types.TokenInfo{AccessToken: "a"}, Available: 1},
+		{Token: types.TokenInfo{AccessToken: "b"}, Available: 5},
+	}
+
+	picked := strategy.PickForKey("", candidates)
+	if picked.Token.AccessToken != "b" {
+		t.Fatalf("期望空sessionKey时退化为HighestAvailable选中b，实际选中%s", picked.Token.AccessToken)
+	}
+}
+
+func TestWeightedByAvailable_Pick_NeverSelectsExhaustedCandidate(t *testing.T) {
+	strategy := NewWeightedByAvailable()
+	candidates := []*CachedToken{
+		{Token: types.TokenInfo{AccessToken: "zero"}, Available: 0},
+		{Token: types.TokenInfo{AccessToken: "only"}, Available: 10},
+	}
+
+	for i := 0; i < 20; i++ {
+		picked := strategy.Pick(candidates)
+		if picked.Token.AccessToken != "only" {
+			t.Fatalf("期望Pick不会选中Available<=0的候选，实际选中%s", picked.Token.AccessToken)
+		}
+	}
+}
+
+func TestWeightedByAvailable_Pick_FallsBackWhenAllZero(t *testing.T) {
+	strategy := NewWeightedByAvailable()
+	candidates := []*CachedToken{
+		{Token: types.TokenInfo{AccessToken: "a"}, Available: 0},
+		{Token: types.TokenInfo{AccessToken: "b"}, Available: 0},
+	}
+
+	picked := strategy.Pick(candidates)
+	if picked == nil {
+		t.Fatalf("期望全部Available<=0时仍降级返回第一个候选，而不是nil")
+	}
+}
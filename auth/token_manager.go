@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"fmt"
 	"kiro2api/config"
 	"kiro2api/logger"
@@ -11,15 +12,34 @@ import (
 
 // TokenManager 简化的token管理器
 type TokenManager struct {
-	cache        *SimpleTokenCache
-	configs      []AuthConfig
-	mutex        sync.RWMutex
-	lastRefresh  time.Time
-	configOrder  []string        // 配置顺序
-	currentIndex int             // 当前使用的token索引
-	exhausted    map[string]bool // 已耗尽的token记录
+	cache          *SimpleTokenCache
+	store          TokenStore           // 共享存储，多副本部署时用于跨实例协调
+	strategy       SelectionStrategy    // token选择策略，默认Sequential
+	configs        []AuthConfig
+	mutex          sync.RWMutex
+	lastRefresh    time.Time
+	configOrder    []string             // 配置顺序
+	currentIndex   int                  // 当前使用的token索引（作为候选收集的起点）
+	exhaustedUntil map[string]time.Time // 已耗尽的token记录：key -> 冷却截止时间（本地视图，最终以store为准）
+	providerCancel context.CancelFunc   // 停止所有动态凭证provider轮询goroutine
+	accountFilter  AccountFilter        // 按subject限制可用账号范围，配合下游APIKey配额使用
 }
 
+// AccountFilter 解析subject（通常是下游APIKey的ID）允许使用的账号范围
+// allowed为空切片表示不限制（可用全部账号）；ok=false表示subject未知，应拒绝该请求
+type AccountFilter func(subject string) (allowed []string, ok bool)
+
+// SetAccountFilter 设置下游调用方的账号过滤器，配合API Key鉴权实现"一个key只能用自己名下的账号"
+func (tm *TokenManager) SetAccountFilter(filter AccountFilter) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	tm.accountFilter = filter
+}
+
+// refreshLockTTL 刷新锁的持有时长，避免持有方异常退出导致锁永久占用
+const refreshLockTTL = 30 * time.Second
+
 // SimpleTokenCache 简化的token缓存（纯数据结构，无锁）
 // 所有并发访问由 TokenManager.mutex 统一管理
 type SimpleTokenCache struct {
@@ -44,8 +64,14 @@ func NewSimpleTokenCache(ttl time.Duration) *SimpleTokenCache {
 	}
 }
 
-// NewTokenManager 创建新的token管理器
+// NewTokenManager 创建新的token管理器（单副本部署，使用进程内存储）
 func NewTokenManager(configs []AuthConfig) *TokenManager {
+	return NewTokenManagerWithStore(configs, NewInMemoryTokenStore())
+}
+
+// NewTokenManagerWithStore 创建新的token管理器，并指定共享存储
+// 多副本部署时传入Redis/etcd等分布式实现的TokenStore，使各副本共享同一账号池状态
+func NewTokenManagerWithStore(configs []AuthConfig, store TokenStore) *TokenManager {
 	// 生成配置顺序
 	configOrder := generateConfigOrder(configs)
 
@@ -53,12 +79,143 @@ func NewTokenManager(configs []AuthConfig) *TokenManager {
 		logger.Int("config_count", len(configs)),
 		logger.Int("config_order_count", len(configOrder)))
 
-	return &TokenManager{
-		cache:        NewSimpleTokenCache(config.TokenCacheTTL),
-		configs:      configs,
-		configOrder:  configOrder,
-		currentIndex: 0,
-		exhausted:    make(map[string]bool),
+	tm := &TokenManager{
+		cache:          NewSimpleTokenCache(config.TokenCacheTTL),
+		store:          store,
+		strategy:       loadStrategyFromEnv(),
+		configs:        configs,
+		configOrder:    configOrder,
+		currentIndex:   0,
+		exhaustedUntil: make(map[string]time.Time),
+	}
+	tm.startCredentialProviders()
+
+	return tm
+}
+
+// SetStrategy 运行时切换token选择策略
+func (tm *TokenManager) SetStrategy(strategy SelectionStrategy) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	tm.strategy = strategy
+	logger.Info("切换token选择策略", logger.String("策略", strategy.Name()))
+}
+
+// Close 停止所有动态凭证provider的后台轮询goroutine
+func (tm *TokenManager) Close() {
+	if tm.providerCancel != nil {
+		tm.providerCancel()
+	}
+}
+
+// startCredentialProviders 为每个配置了Provider的账号启动一个后台轮询goroutine
+func (tm *TokenManager) startCredentialProviders() {
+	ctx, cancel := context.WithCancel(context.Background())
+	tm.providerCancel = cancel
+
+	for index, cfg := range tm.configs {
+		if cfg.Provider == nil {
+			continue
+		}
+
+		provider, err := NewCredentialProvider(*cfg.Provider)
+		if err != nil {
+			logger.Warn("创建凭证提供方失败", logger.Int("index", index), logger.Err(err))
+			continue
+		}
+
+		// 有原生变更通知机制的供应方（如文件监视）按事件驱动触发刷新，不走固定间隔轮询
+		if watchable, ok := provider.(WatchableCredentialProvider); ok {
+			logger.Info("启动动态凭证文件监视",
+				logger.Int("index", index),
+				logger.String("provider", provider.Type()))
+
+			go tm.runWatchableCredentialProvider(ctx, index, watchable)
+			continue
+		}
+
+		interval := parseRefreshEvery(cfg.Provider.RefreshEvery)
+		logger.Info("启动动态凭证轮询",
+			logger.Int("index", index),
+			logger.String("provider", provider.Type()),
+			logger.Any("interval", interval))
+
+		go tm.runCredentialProvider(ctx, index, provider, interval)
+	}
+}
+
+// runCredentialProvider 按interval周期性调用provider.Fetch，直到ctx被取消
+func (tm *TokenManager) runCredentialProvider(ctx context.Context, index int, provider CredentialProvider, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tm.refreshFromProvider(index, provider)
+		}
+	}
+}
+
+// runWatchableCredentialProvider 先主动拉取一次凭证，再把后续刷新完全交给provider的事件通知驱动，
+// 直到ctx被取消；相比固定间隔轮询，凭证轮换后能立即生效而不必等到下一个tick
+func (tm *TokenManager) runWatchableCredentialProvider(ctx context.Context, index int, provider WatchableCredentialProvider) {
+	tm.refreshFromProvider(index, provider)
+	provider.Watch(ctx, func() {
+		tm.refreshFromProvider(index, provider)
+	})
+}
+
+// refreshFromProvider 拉取一次凭证，若refreshToken发生变化则热替换对应配置并使该账号的缓存立即失效
+func (tm *TokenManager) refreshFromProvider(index int, provider CredentialProvider) {
+	payload, err := provider.Fetch(context.Background())
+	if err != nil {
+		logger.Warn("拉取动态凭证失败",
+			logger.Int("index", index),
+			logger.String("provider", provider.Type()),
+			logger.Err(err))
+		return
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if index >= len(tm.configs) {
+		return
+	}
+
+	cfg := &tm.configs[index]
+	if payload.RefreshToken == cfg.RefreshToken {
+		return
+	}
+
+	logger.Info("检测到轮换后的凭证，热替换账号配置",
+		logger.Int("index", index),
+		logger.String("provider", provider.Type()))
+
+	cfg.RefreshToken = payload.RefreshToken
+	if payload.ClientID != "" {
+		cfg.ClientID = payload.ClientID
+	}
+	if payload.ClientSecret != "" {
+		cfg.ClientSecret = payload.ClientSecret
+	}
+
+	// 旧token已被轮换替换，清掉缓存槽位并立即用新凭证刷新，
+	// 这样旧的（可能已耗尽）token会被新凭证直接取代，而不必等待下一次按需刷新
+	cacheKey := fmt.Sprintf(config.TokenCacheKeyFormat, index)
+	delete(tm.cache.tokens, cacheKey)
+	delete(tm.exhaustedUntil, cacheKey)
+	// 同时摘除共享存储上的耗尽标记，否则分布式部署下新凭证仍会被其它副本当作耗尽状态，
+	// 直到旧凭证的冷却期自然到期
+	if err := tm.store.ClearExhausted(cacheKey); err != nil {
+		logger.Warn("摘除共享存储耗尽标记失败", logger.String("key", cacheKey), logger.Err(err))
+	}
+	if err := tm.refreshSingleTokenByIndex(index); err != nil {
+		logger.Warn("使用轮换后的凭证刷新失败", logger.Int("index", index), logger.Err(err))
 	}
 }
 
@@ -68,98 +225,348 @@ func (tm *TokenManager) getBestToken() (types.TokenInfo, error) {
 	tm.mutex.Lock()
 	defer tm.mutex.Unlock()
 
+	return tm.getBestTokenUnlocked("")
+}
+
+// GetBestTokenForSession 获取token时携带一个会话/请求key
+// 配合StickyBySession策略，使同一会话在其生命周期内稳定落在同一账号上
+func (tm *TokenManager) GetBestTokenForSession(sessionKey string) (types.TokenInfo, error) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	return tm.getBestTokenUnlocked(sessionKey)
+}
+
+// GetBestTokenFor 按subject（通常是下游APIKey的ID）的允许账号范围选取token
+// 配合SetAccountFilter使用，使每个下游调用方只能使用自己名下的上游账号；
+// 未设置过滤器或subject被允许使用全部账号时，行为与GetBestTokenForSession("")等价
+func (tm *TokenManager) GetBestTokenFor(subject string) (types.TokenInfo, error) {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if tm.accountFilter == nil {
+		return tm.getBestTokenUnlocked("")
+	}
+
+	allowed, ok := tm.accountFilter(subject)
+	if !ok {
+		return types.TokenInfo{}, fmt.Errorf("未知的subject: %s", subject)
+	}
+	if len(allowed) == 0 {
+		return tm.getBestTokenUnlocked("")
+	}
+
+	return tm.getBestTokenForAccountsUnlocked(allowed)
+}
+
+// getBestTokenForAccountsUnlocked 仅在allowedIDs对应的账号范围内选取并租用一个可用token
+// 内部方法：调用者必须持有 tm.mutex
+func (tm *TokenManager) getBestTokenForAccountsUnlocked(allowedIDs []string) (types.TokenInfo, error) {
+	allowedKeys := make(map[string]bool, len(allowedIDs))
+	for _, id := range allowedIDs {
+		if key, found := tm.cacheKeyForAccountIDUnlocked(id); found {
+			allowedKeys[key] = true
+		}
+	}
+	if len(allowedKeys) == 0 {
+		return types.TokenInfo{}, fmt.Errorf("允许使用的账号均无法解析，请检查allowedAccounts配置")
+	}
+
+	candidates, keys := tm.refreshAndCollectCandidatesUnlocked()
+
+	var filteredCandidates []*CachedToken
+	var filteredKeys []string
+	for i, key := range keys {
+		if allowedKeys[key] {
+			filteredCandidates = append(filteredCandidates, candidates[i])
+			filteredKeys = append(filteredKeys, key)
+		}
+	}
+	if len(filteredCandidates) == 0 {
+		return types.TokenInfo{}, fmt.Errorf("该subject允许使用的账号当前均不可用")
+	}
+
+	picked := tm.strategy.Pick(filteredCandidates)
+	if picked == nil {
+		return types.TokenInfo{}, fmt.Errorf("没有可用的token")
+	}
+
+	for i, c := range filteredCandidates {
+		if c != picked {
+			continue
+		}
+
+		cacheKey := filteredKeys[i]
+		picked.LastUsed = time.Now()
+
+		// Available的扣减统一由store.Lease完成（单副本下store与本地缓存共享同一*CachedToken），
+		// 这里不再重复本地自减，否则单副本部署会把每次请求的配额扣减两次；
+		// Lease返回的remaining是权威值，必须写回本地缓存，否则分布式部署下
+		// WeightedByAvailable/HighestAvailable会基于过期的本地Available做选择
+		if remaining, ok, err := tm.store.Lease(cacheKey, 1); err != nil {
+			logger.Warn("共享存储扣减配额失败", logger.String("key", cacheKey), logger.Err(err))
+		} else {
+			picked.Available = remaining
+			if !ok {
+				tm.markExhaustedUnlocked(cacheKey)
+			} else {
+				tm.strategy.OnResult(picked, OutcomeSuccess)
+			}
+		}
+
+		return picked.Token, nil
+	}
+
+	return types.TokenInfo{}, fmt.Errorf("没有可用的token")
+}
+
+// cacheKeyForAccountIDUnlocked 将账号标识（AuthConfig.ID，或未设置ID时的cache key本身）解析为其cache key
+// 内部方法：调用者必须持有 tm.mutex
+func (tm *TokenManager) cacheKeyForAccountIDUnlocked(id string) (string, bool) {
+	for i, cfg := range tm.configs {
+		key := fmt.Sprintf(config.TokenCacheKeyFormat, i)
+		if cfg.ID == id || key == id {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// getBestTokenUnlocked 选择并租用一个可用token
+// 内部方法：调用者必须持有 tm.mutex
+func (tm *TokenManager) getBestTokenUnlocked(sessionKey string) (types.TokenInfo, error) {
 	// 选择最优token（内部方法，不加锁）
-	bestToken := tm.selectBestTokenUnlocked()
+	bestToken, cacheKey := tm.selectBestTokenUnlocked(sessionKey)
 	if bestToken == nil {
 		return types.TokenInfo{}, fmt.Errorf("没有可用的token")
 	}
 
 	// 更新最后使用时间（在锁内，安全）
 	bestToken.LastUsed = time.Now()
-	if bestToken.Available > 0 {
-		bestToken.Available--
+
+	// 通过共享存储原子扣减Available，使多副本共享同一账号的剩余配额；
+	// 单副本部署下store与本地缓存指向同一个*CachedToken，Lease本身就会把本地Available一并扣减，
+	// 这里不再额外自减，否则会对同一次请求重复扣减配额；
+	// Lease返回的remaining是权威值，必须写回本地缓存，否则分布式部署下
+	// WeightedByAvailable/HighestAvailable会基于过期的本地Available做选择
+	if remaining, ok, err := tm.store.Lease(cacheKey, 1); err != nil {
+		logger.Warn("共享存储扣减配额失败", logger.String("key", cacheKey), logger.Err(err))
+	} else {
+		bestToken.Available = remaining
+		if !ok {
+			tm.markExhaustedUnlocked(cacheKey)
+		} else {
+			tm.strategy.OnResult(bestToken, OutcomeSuccess)
+		}
 	}
 
 	return bestToken.Token, nil
 }
 
-// selectBestTokenUnlocked 按配置顺序选择下一个可用token
+// ReportTokenResult 由调用方在请求完成后上报某个token的使用结果
+// 当resultErr被判定为token失效（上游返回401/AccessTokenExpired/InvalidGrant，参见IsAuthExpiredError）时，
+// 立即强制刷新该token对应的缓存项（跳过TTL检查），刷新失败则将其标记为耗尽，
+// 并返回下一个可用token供调用方重试一次。这填补了"本地TTL未到期但Kiro已提前使token失效"的空窗期。
+// resultErr为nil或不属于token失效场景时，原样返回传入的token。
+func (tm *TokenManager) ReportTokenResult(token types.TokenInfo, resultErr error) (types.TokenInfo, error) {
+	if !IsAuthExpiredError(resultErr) {
+		return token, nil
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	cacheKey, found := tm.findCacheKeyByTokenUnlocked(token)
+	if !found {
+		logger.Warn("未找到上报token对应的缓存项，直接选取下一个可用token", logger.Err(resultErr))
+		return tm.getBestTokenUnlocked("")
+	}
+
+	index, found := tm.indexForCacheKeyUnlocked(cacheKey)
+	if !found {
+		logger.Warn("上报token缺少对应的配置索引", logger.String("key", cacheKey))
+		return tm.getBestTokenUnlocked("")
+	}
+
+	logger.Info("收到上游token失效上报，强制刷新", logger.String("key", cacheKey), logger.Err(resultErr))
+	if err := tm.refreshSingleTokenByIndex(index); err != nil {
+		logger.Warn("强制刷新失效token失败，标记为耗尽", logger.String("key", cacheKey), logger.Err(err))
+		tm.markExhaustedUnlocked(cacheKey)
+	}
+
+	return tm.getBestTokenUnlocked("")
+}
+
+// findCacheKeyByTokenUnlocked 根据token内容反查其在缓存中对应的key
+// 内部方法：调用者必须持有 tm.mutex
+func (tm *TokenManager) findCacheKeyByTokenUnlocked(token types.TokenInfo) (string, bool) {
+	for key, cached := range tm.cache.tokens {
+		if cached.Token.AccessToken == token.AccessToken {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// indexForCacheKeyUnlocked 根据cache key反查其在configOrder中的索引
 // 内部方法：调用者必须持有 tm.mutex
-// 重构说明：从selectBestToken改为Unlocked后缀，明确锁约定
-func (tm *TokenManager) selectBestTokenUnlocked() *CachedToken {
+func (tm *TokenManager) indexForCacheKeyUnlocked(cacheKey string) (int, bool) {
+	for i, key := range tm.configOrder {
+		if key == cacheKey {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// selectBestTokenUnlocked 收集当前可用的候选token，交给tm.strategy选出下一个要用的token
+// sessionKey非空且当前策略支持KeyedSelectionStrategy时，优先按sessionKey做稳定选择
+// 返回选中的token及其cache key；内部方法：调用者必须持有 tm.mutex
+func (tm *TokenManager) selectBestTokenUnlocked(sessionKey string) (*CachedToken, string) {
 	// 调用者已持有 tm.mutex，无需额外加锁
 
 	// 如果没有配置顺序，降级到按map遍历顺序
 	if len(tm.configOrder) == 0 {
 		for key, cached := range tm.cache.tokens {
-			if time.Since(cached.CachedAt) <= tm.cache.ttl && cached.IsUsable() {
-				logger.Debug("顺序策略选择token（无顺序配置）",
+			if time.Since(cached.CachedAt) <= tm.cache.ttl && cached.IsUsable() && !tm.isExhaustedUnlocked(key) {
+				logger.Debug("选择token（无顺序配置）",
 					logger.String("selected_key", key),
 					logger.Float64("available_count", cached.Available))
-				return cached
+				return cached, key
 			}
 		}
-		return nil
-	}
-
-	// 从当前索引开始，找到第一个可用的token
-	for attempts := 0; attempts < len(tm.configOrder); attempts++ {
-		currentKey := tm.configOrder[tm.currentIndex]
-
-		// 检查这个token是否存在且可用
-		if cached, exists := tm.cache.tokens[currentKey]; exists {
-			// 检查token是否过期
-			if time.Since(cached.CachedAt) > tm.cache.ttl {
-				// 刷新这个token
-				if err := tm.refreshSingleTokenByIndex(tm.currentIndex); err != nil {
-					logger.Warn("刷新token失败", logger.String("key", currentKey), logger.Err(err))
-					tm.exhausted[currentKey] = true
-					tm.currentIndex = (tm.currentIndex + 1) % len(tm.configOrder)
-					continue
+		return nil, ""
+	}
+
+	candidates, keys := tm.refreshAndCollectCandidatesUnlocked()
+	if len(candidates) == 0 {
+		logger.Warn("所有token都不可用", logger.Int("total_count", len(tm.configOrder)))
+		return nil, ""
+	}
+
+	var picked *CachedToken
+	if keyed, ok := tm.strategy.(KeyedSelectionStrategy); ok && sessionKey != "" {
+		picked = keyed.PickForKey(sessionKey, candidates)
+	} else {
+		picked = tm.strategy.Pick(candidates)
+	}
+	if picked == nil {
+		return nil, ""
+	}
+
+	for i, c := range candidates {
+		if c != picked {
+			continue
+		}
+
+		logger.Debug("策略选择token",
+			logger.String("strategy", tm.strategy.Name()),
+			logger.String("selected_key", keys[i]),
+			logger.Float64("available_count", c.Available))
+
+		// 把currentIndex停在选中账号上，作为下一轮候选收集的起点：
+		// Sequential策略因此会持续使用同一账号，直到它耗尽或不可用才顺延到下一个
+		if index, found := tm.indexForCacheKeyUnlocked(keys[i]); found {
+			tm.currentIndex = index
+		}
+
+		return c, keys[i]
+	}
+
+	return nil, ""
+}
+
+// refreshAndCollectCandidatesUnlocked 从currentIndex开始遍历configOrder一整圈，
+// 刷新过期/缺失的token，返回所有当前可用（未耗尽、未过期）的候选token及其cache key
+// 内部方法：调用者必须持有 tm.mutex
+func (tm *TokenManager) refreshAndCollectCandidatesUnlocked() ([]*CachedToken, []string) {
+	var candidates []*CachedToken
+	var keys []string
+
+	start := tm.currentIndex
+	for offset := 0; offset < len(tm.configOrder); offset++ {
+		index := (start + offset) % len(tm.configOrder)
+		key := tm.configOrder[index]
+
+		if tm.isExhaustedUnlocked(key) {
+			continue
+		}
+
+		cached, exists := tm.cache.tokens[key]
+		if !exists || time.Since(cached.CachedAt) > tm.cache.ttl {
+			if err := tm.refreshSingleTokenByIndex(index); err != nil {
+				logger.Warn("刷新token失败", logger.String("key", key), logger.Err(err))
+				if exists {
+					tm.strategy.OnResult(cached, OutcomeRefreshFailed)
 				}
-				cached = tm.cache.tokens[currentKey]
+				tm.markExhaustedUnlocked(key)
+				continue
 			}
+			cached = tm.cache.tokens[key]
+		}
 
-			// 检查token是否可用
-			if cached.IsUsable() {
-				logger.Debug("顺序策略选择token",
-					logger.String("selected_key", currentKey),
-					logger.Int("index", tm.currentIndex),
-					logger.Float64("available_count", cached.Available))
-				return cached
-			}
+		if cached != nil && cached.IsUsable() {
+			candidates = append(candidates, cached)
+			keys = append(keys, key)
 		} else {
-			// token不存在，刷新它
-			if err := tm.refreshSingleTokenByIndex(tm.currentIndex); err != nil {
-				logger.Warn("刷新token失败", logger.String("key", currentKey), logger.Err(err))
-				tm.exhausted[currentKey] = true
-				tm.currentIndex = (tm.currentIndex + 1) % len(tm.configOrder)
-				continue
-			}
-			if cached, exists := tm.cache.tokens[currentKey]; exists && cached.IsUsable() {
-				logger.Debug("顺序策略选择token",
-					logger.String("selected_key", currentKey),
-					logger.Int("index", tm.currentIndex),
-					logger.Float64("available_count", cached.Available))
-				return cached
-			}
+			tm.markExhaustedUnlocked(key)
 		}
+	}
 
-		// 标记当前token为已耗尽，移动到下一个
-		tm.exhausted[currentKey] = true
-		tm.currentIndex = (tm.currentIndex + 1) % len(tm.configOrder)
+	return candidates, keys
+}
 
-		logger.Debug("token不可用，切换到下一个",
-			logger.String("exhausted_key", currentKey),
-			logger.Int("next_index", tm.currentIndex))
+// isExhaustedUnlocked 判断key是否已耗尽，优先以共享存储为准，兼顾本地视图
+// 本地冷却期(exhaustedUntil)一旦过期，会摘除标记并做一次性重新探测，而不是永久耗尽
+// 内部方法：调用者必须持有 tm.mutex
+func (tm *TokenManager) isExhaustedUnlocked(key string) bool {
+	if exhausted, err := tm.store.IsExhausted(key); err == nil {
+		if exhausted {
+			return true
+		}
+	} else {
+		logger.Warn("查询共享存储耗尽状态失败", logger.String("key", key), logger.Err(err))
 	}
 
-	// 所有token都不可用
-	logger.Warn("所有token都不可用",
-		logger.Int("total_count", len(tm.configOrder)),
-		logger.Int("exhausted_count", len(tm.exhausted)))
+	until, marked := tm.exhaustedUntil[key]
+	if !marked {
+		return false
+	}
+	if time.Now().Before(until) {
+		return true
+	}
 
-	return nil
+	// 冷却期已过，摘除本地标记并做一次性重新探测，给之前耗尽的账号一个恢复的机会
+	delete(tm.exhaustedUntil, key)
+	index, found := tm.indexForCacheKeyUnlocked(key)
+	if !found {
+		return false
+	}
+	if err := tm.refreshSingleTokenByIndex(index); err != nil {
+		logger.Warn("耗尽账号重新探测失败", logger.String("key", key), logger.Err(err))
+		tm.markExhaustedUnlocked(key)
+		return true
+	}
+
+	return false
+}
+
+// markExhaustedUnlocked 将key标记为耗尽，同时更新本地冷却期与共享存储，
+// 并通知当前策略该token的结果为OutcomeExhausted
+// 内部方法：调用者必须持有 tm.mutex
+func (tm *TokenManager) markExhaustedUnlocked(key string) {
+	until := time.Now().Add(config.TokenCacheTTL)
+	tm.exhaustedUntil[key] = until
+	if err := tm.store.MarkExhausted(key, until); err != nil {
+		logger.Warn("标记token耗尽失败", logger.String("key", key), logger.Err(err))
+	}
+
+	if cached, exists := tm.cache.tokens[key]; exists {
+		tm.strategy.OnResult(cached, OutcomeExhausted)
+	}
 }
 
 // refreshSingleTokenByIndex 刷新指定索引的token
@@ -174,6 +581,29 @@ func (tm *TokenManager) refreshSingleTokenByIndex(index int) error {
 		return fmt.Errorf("token已禁用")
 	}
 
+	cacheKey := fmt.Sprintf(config.TokenCacheKeyFormat, index)
+
+	// 多副本场景下，同一refreshToken同一时刻只允许一个副本刷新
+	// 未抢到锁时，直接尝试读取其他副本刚发布的结果，避免重复刷新同一refreshToken
+	acquired, lockErr := tm.store.AcquireRefreshLock(cacheKey, refreshLockTTL)
+	if lockErr != nil {
+		logger.Warn("获取刷新锁失败，继续本地刷新", logger.String("key", cacheKey), logger.Err(lockErr))
+		acquired = true
+	}
+	if !acquired {
+		if remote, ok, getErr := tm.store.Get(cacheKey); getErr == nil && ok {
+			logger.Debug("其他副本已刷新该token，直接同步", logger.String("key", cacheKey))
+			tm.cache.tokens[cacheKey] = remote
+			return nil
+		}
+		return fmt.Errorf("其他副本正在刷新该token")
+	}
+	defer func() {
+		if err := tm.store.ReleaseRefreshLock(cacheKey); err != nil {
+			logger.Warn("释放刷新锁失败", logger.String("key", cacheKey), logger.Err(err))
+		}
+	}()
+
 	// 刷新token
 	token, err := tm.refreshSingleToken(cfg)
 	if err != nil {
@@ -192,14 +622,19 @@ func (tm *TokenManager) refreshSingleTokenByIndex(index int) error {
 		logger.Warn("检查使用限制失败", logger.Err(checkErr))
 	}
 
-	// 更新缓存
-	cacheKey := fmt.Sprintf(config.TokenCacheKeyFormat, index)
-	tm.cache.tokens[cacheKey] = &CachedToken{
+	// 更新本地缓存
+	cached := &CachedToken{
 		Token:     token,
 		UsageInfo: usageInfo,
 		CachedAt:  time.Now(),
 		Available: available,
 	}
+	tm.cache.tokens[cacheKey] = cached
+
+	// 发布到共享存储，使其他副本无需重启即可看到新账号/新token
+	if err := tm.store.Set(cacheKey, cached, tm.cache.ttl); err != nil {
+		logger.Warn("发布token到共享存储失败", logger.String("key", cacheKey), logger.Err(err))
+	}
 
 	logger.Debug("token缓存更新",
 		logger.String("cache_key", cacheKey),
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"kiro2api/types"
+)
+
+// TestGetBestTokenUnlocked_DecrementsAvailableOnce 回归测试：单副本部署下（默认的
+// InMemoryTokenStore），getBestTokenUnlocked每次只能把Available扣减一次。
+// 此前的实现会先本地自减bestToken.Available，再调用store.Lease对同一个*CachedToken
+// 指针再扣一次，导致单副本部署下每次请求都把配额多扣了一倍。
+func TestGetBestTokenUnlocked_DecrementsAvailableOnce(t *testing.T) {
+	store := NewInMemoryTokenStore()
+	cacheKey := "account-0"
+	cached := &CachedToken{
+		Token:     types.TokenInfo{AccessToken: "tok-1", ExpiresAt: time.Now().Add(time.Hour)},
+		CachedAt:  time.Now(),
+		Available: 5,
+	}
+
+	// InMemoryTokenStore.Set与tm.cache.tokens保存的是同一个*CachedToken指针，
+	// 这与refreshSingleTokenByIndex中"先写本地缓存，再Set到共享存储"的真实用法一致
+	if err := store.Set(cacheKey, cached, time.Hour); err != nil {
+		t.Fatalf("seed store: %v", err)
+	}
+
+	tm := &TokenManager{
+		cache:          &SimpleTokenCache{tokens: map[string]*CachedToken{cacheKey: cached}, ttl: time.Hour},
+		store:          store,
+		strategy:       &Sequential{},
+		configs:        []AuthConfig{{RefreshToken: "r"}},
+		configOrder:    []string{cacheKey},
+		exhaustedUntil: make(map[string]time.Time),
+	}
+
+	if _, err := tm.getBestTokenUnlocked(""); err != nil {
+		t.Fatalf("getBestTokenUnlocked: %v", err)
+	}
+
+	if cached.Available != 4 {
+		t.Fatalf("expected Available to be decremented exactly once to 4, got %v", cached.Available)
+	}
+}
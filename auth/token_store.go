@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenStore 抽象token共享存储
+// 单副本部署下可以使用纯内存实现；多副本部署时，通过共享存储（Redis/etcd等）
+// 协调各副本对同一账号池的访问，避免重复刷新同一refreshToken、重复消耗同一账号的配额
+type TokenStore interface {
+	// Get 读取指定key对应的缓存token，ok为false表示不存在或已过期
+	Get(key string) (token *CachedToken, ok bool, err error)
+	// Set 写入/覆盖指定key的缓存token，ttl<=0表示永不过期
+	Set(key string, token *CachedToken, ttl time.Duration) error
+	// MarkExhausted 将key标记为耗尽，直到until之前都不会被选中
+	MarkExhausted(key string, until time.Time) error
+	// IsExhausted 判断key当前是否处于耗尽冷却期
+	IsExhausted(key string) (bool, error)
+	// ClearExhausted 主动摘除key的耗尽标记，用于凭证轮换后让新凭证立即可被选中，
+	// 而不必等待旧凭证的冷却期自然到期
+	ClearExhausted(key string) error
+	// Lease 原子性地从key对应的Available中扣减n，返回扣减后的权威剩余值及是否仍然可用（>0）
+	// 多副本场景下，Available是跨副本共享的计数器，必须通过原子操作扣减；
+	// 调用方应把remaining写回本地*CachedToken.Available，否则按Available排序/加权的
+	// 选择策略（WeightedByAvailable/HighestAvailable）在分布式存储下会用到过期的本地值
+	Lease(key string, n float64) (remaining float64, ok bool, err error)
+	// AcquireRefreshLock 尝试获取key对应的刷新锁（SETNX语义），ttl后自动释放
+	// 用于保证同一refreshToken在同一时刻只有一个副本在执行刷新
+	AcquireRefreshLock(key string, ttl time.Duration) (acquired bool, err error)
+	// ReleaseRefreshLock 主动释放刷新锁
+	ReleaseRefreshLock(key string) error
+}
+
+// InMemoryTokenStore 单进程内存实现，行为与重构前的TokenManager本地状态一致
+// 默认使用此实现，无需额外依赖
+type InMemoryTokenStore struct {
+	mutex     sync.Mutex
+	tokens    map[string]*CachedToken
+	expiresAt map[string]time.Time
+	exhausted map[string]time.Time
+	locks     map[string]time.Time
+}
+
+// NewInMemoryTokenStore 创建内存版TokenStore
+func NewInMemoryTokenStore() *InMemoryTokenStore {
+	return &InMemoryTokenStore{
+		tokens:    make(map[string]*CachedToken),
+		expiresAt: make(map[string]time.Time),
+		exhausted: make(map[string]time.Time),
+		locks:     make(map[string]time.Time),
+	}
+}
+
+func (s *InMemoryTokenStore) Get(key string) (*CachedToken, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	token, exists := s.tokens[key]
+	if !exists {
+		return nil, false, nil
+	}
+	if expiresAt, hasTTL := s.expiresAt[key]; hasTTL && time.Now().After(expiresAt) {
+		delete(s.tokens, key)
+		delete(s.expiresAt, key)
+		return nil, false, nil
+	}
+
+	return token, true, nil
+}
+
+func (s *InMemoryTokenStore) Set(key string, token *CachedToken, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tokens[key] = token
+	if ttl > 0 {
+		s.expiresAt[key] = time.Now().Add(ttl)
+	} else {
+		delete(s.expiresAt, key)
+	}
+
+	return nil
+}
+
+func (s *InMemoryTokenStore) MarkExhausted(key string, until time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.exhausted[key] = until
+	return nil
+}
+
+func (s *InMemoryTokenStore) IsExhausted(key string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	until, marked := s.exhausted[key]
+	if !marked {
+		return false, nil
+	}
+	if time.Now().After(until) {
+		delete(s.exhausted, key)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (s *InMemoryTokenStore) ClearExhausted(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.exhausted, key)
+	return nil
+}
+
+func (s *InMemoryTokenStore) Lease(key string, n float64) (float64, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	token, exists := s.tokens[key]
+	if !exists {
+		return 0, false, nil
+	}
+
+	if token.Available > 0 {
+		token.Available -= n
+	}
+
+	return token.Available, token.Available > 0, nil
+}
+
+func (s *InMemoryTokenStore) AcquireRefreshLock(key string, ttl time.Duration) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if until, locked := s.locks[key]; locked && time.Now().Before(until) {
+		return false, nil
+	}
+
+	s.locks[key] = time.Now().Add(ttl)
+	return true, nil
+}
+
+func (s *InMemoryTokenStore) ReleaseRefreshLock(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.locks, key)
+	return nil
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"kiro2api/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// leaseScript 原子性地扣减Available并返回扣减后的值
+// Redis的DECRBY只支持整数，这里用Lua脚本模拟浮点数的原子扣减，
+// 同时把结果写回hash字段，避免多个副本之间出现"读取-扣减-写回"的竞态
+var leaseScript = redis.NewScript(`
+local raw = redis.call("HGET", KEYS[1], "available")
+if raw == false then
+	return -1
+end
+local available = tonumber(raw) - tonumber(ARGV[1])
+redis.call("HSET", KEYS[1], "available", tostring(available))
+return tostring(available)
+`)
+
+// RedisTokenStore 基于Redis的分布式TokenStore实现
+// 所有key都会加上namespace前缀，便于同一套Redis承载多个kiro2api集群
+// 实现方式同样适用于兼容Redis协议的etcd网关（如etcd-redis-proxy）
+type RedisTokenStore struct {
+	client    redis.Cmdable
+	namespace string
+}
+
+// NewRedisTokenStore 创建Redis版TokenStore
+// namespace 用于隔离不同集群/环境的key空间，例如"kiro2api:prod"
+func NewRedisTokenStore(client redis.Cmdable, namespace string) *RedisTokenStore {
+	return &RedisTokenStore{
+		client:    client,
+		namespace: namespace,
+	}
+}
+
+func (s *RedisTokenStore) tokenKey(key string) string {
+	return fmt.Sprintf("%s:token:%s", s.namespace, key)
+}
+
+func (s *RedisTokenStore) exhaustedKey(key string) string {
+	return fmt.Sprintf("%s:exhausted:%s", s.namespace, key)
+}
+
+func (s *RedisTokenStore) lockKey(key string) string {
+	return fmt.Sprintf("%s:refreshlock:%s", s.namespace, key)
+}
+
+func (s *RedisTokenStore) Get(key string) (*CachedToken, bool, error) {
+	ctx := context.Background()
+
+	raw, err := s.client.HGet(ctx, s.tokenKey(key), "data").Result()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("从redis读取token失败: %w", err)
+	}
+
+	var cached CachedToken
+	if err := json.Unmarshal([]byte(raw), &cached); err != nil {
+		return nil, false, fmt.Errorf("解析redis中的token失败: %w", err)
+	}
+
+	available, err := s.client.HGet(ctx, s.tokenKey(key), "available").Float64()
+	if err == nil {
+		cached.Available = available
+	}
+
+	return &cached, true, nil
+}
+
+func (s *RedisTokenStore) Set(key string, token *CachedToken, ttl time.Duration) error {
+	ctx := context.Background()
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("序列化token失败: %w", err)
+	}
+
+	redisKey := s.tokenKey(key)
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, redisKey, "data", data, "available", token.Available)
+	if ttl > 0 {
+		pipe.Expire(ctx, redisKey, ttl)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("写入redis失败: %w", err)
+	}
+
+	logger.Debug("token已发布到共享存储",
+		logger.String("key", key),
+		logger.Float64("available", token.Available))
+
+	return nil
+}
+
+func (s *RedisTokenStore) MarkExhausted(key string, until time.Time) error {
+	ctx := context.Background()
+
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+
+	if err := s.client.Set(ctx, s.exhaustedKey(key), until.Unix(), ttl).Err(); err != nil {
+		return fmt.Errorf("标记token耗尽失败: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisTokenStore) IsExhausted(key string) (bool, error) {
+	ctx := context.Background()
+
+	exists, err := s.client.Exists(ctx, s.exhaustedKey(key)).Result()
+	if err != nil {
+		return false, fmt.Errorf("查询token耗尽状态失败: %w", err)
+	}
+
+	return exists > 0, nil
+}
+
+func (s *RedisTokenStore) ClearExhausted(key string) error {
+	ctx := context.Background()
+
+	if err := s.client.Del(ctx, s.exhaustedKey(key)).Err(); err != nil {
+		return fmt.Errorf("摘除token耗尽标记失败: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisTokenStore) Lease(key string, n float64) (float64, bool, error) {
+	ctx := context.Background()
+
+	result, err := leaseScript.Run(ctx, s.client, []string{s.tokenKey(key)}, n).Result()
+	if err != nil {
+		return 0, false, fmt.Errorf("扣减共享配额失败: %w", err)
+	}
+
+	remaining, ok := result.(string)
+	if !ok || remaining == "-1" {
+		return 0, false, nil
+	}
+
+	var available float64
+	if _, err := fmt.Sscanf(remaining, "%f", &available); err != nil {
+		return 0, false, fmt.Errorf("解析剩余配额失败: %w", err)
+	}
+
+	return available, available > 0, nil
+}
+
+func (s *RedisTokenStore) AcquireRefreshLock(key string, ttl time.Duration) (bool, error) {
+	ctx := context.Background()
+
+	acquired, err := s.client.SetNX(ctx, s.lockKey(key), 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("获取刷新锁失败: %w", err)
+	}
+
+	return acquired, nil
+}
+
+func (s *RedisTokenStore) ReleaseRefreshLock(key string) error {
+	ctx := context.Background()
+
+	if err := s.client.Del(ctx, s.lockKey(key)).Err(); err != nil {
+		return fmt.Errorf("释放刷新锁失败: %w", err)
+	}
+
+	return nil
+}
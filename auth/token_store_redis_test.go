@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// TestRedisTokenStore_Lease 验证Lease对Redis中共享的Available做原子扣减，
+// 并返回扣减后的权威剩余值，供调用方写回本地*CachedToken
+func TestRedisTokenStore_Lease(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("启动miniredis失败: %v", err)
+	}
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	store := NewRedisTokenStore(client, "test")
+	if err := store.Set("account-0", &CachedToken{Available: 3}, time.Hour); err != nil {
+		t.Fatalf("写入种子数据失败: %v", err)
+	}
+
+	remaining, ok, err := store.Lease("account-0", 1)
+	if err != nil {
+		t.Fatalf("Lease返回错误: %v", err)
+	}
+	if !ok || remaining != 2 {
+		t.Fatalf("期望remaining=2且ok=true，实际remaining=%v ok=%v", remaining, ok)
+	}
+
+	remaining, ok, err = store.Lease("account-0", 3)
+	if err != nil {
+		t.Fatalf("Lease返回错误: %v", err)
+	}
+	if ok {
+		t.Fatalf("配额已超支，期望ok=false，实际remaining=%v ok=%v", remaining, ok)
+	}
+}
+
+// TestRedisTokenStore_Lease_UnknownKey 验证对不存在的key做Lease时安全返回false而非报错
+func TestRedisTokenStore_Lease_UnknownKey(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("启动miniredis失败: %v", err)
+	}
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	store := NewRedisTokenStore(client, "test")
+
+	_, ok, err := store.Lease("missing", 1)
+	if err != nil {
+		t.Fatalf("Lease返回错误: %v", err)
+	}
+	if ok {
+		t.Fatalf("期望不存在的key返回ok=false")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"kiro2api/auth"
+)
+
+// main 分发kiro2api的子命令。目前只有encrypt-config一个子命令；
+// 不带子命令运行的主服务启动流程由本仓库其他入口负责，不在本文件范围内
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "encrypt-config":
+		if err := runEncryptConfig(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "encrypt-config失败:", err)
+			os.Exit(1)
+		}
+	case "-h", "--help", "help":
+		printUsage()
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令: %s\n\n", os.Args[1])
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+// runEncryptConfig 解析encrypt-config子命令的参数并调用auth.RunEncryptConfig
+func runEncryptConfig(args []string) error {
+	fs := flag.NewFlagSet("encrypt-config", flag.ExitOnError)
+	input := fs.String("in", "", "明文JSON或CSV配置文件路径（必填）")
+	output := fs.String("out", "", "加密后文件的输出路径（必填）")
+	algorithm := fs.String("algorithm", auth.AlgorithmAES256GCM, "加密算法: AES-256-GCM 或 ChaCha20-Poly1305（区分大小写）")
+	kid := fs.String("kid", "", "密钥标识，决定使用哪个密钥加密（必填）")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return auth.RunEncryptConfig(auth.EncryptConfigOptions{
+		InputPath:  *input,
+		OutputPath: *output,
+		Algorithm:  *algorithm,
+		Kid:        *kid,
+	})
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "用法: kiro2api encrypt-config -in <明文配置> -out <加密输出> -kid <密钥标识> [-algorithm AES-256-GCM|ChaCha20-Poly1305]")
+}